@@ -2,14 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/victorkt/mongo-driver-cookbook/mongoerr"
+	"github.com/victorkt/mongo-driver-cookbook/mongopage"
+	"github.com/victorkt/mongo-driver-cookbook/mongoquery"
+	"github.com/victorkt/mongo-driver-cookbook/mongorepo"
+	"github.com/victorkt/mongo-driver-cookbook/mongosession"
 )
 
 type Post struct {
@@ -22,6 +31,61 @@ type Post struct {
 	UpdatedAt *time.Time         `bson:"updated_at"`
 }
 
+// GetID and SetID implement mongorepo.Identifiable, so Repository[Post]
+// reads and writes Post.ID explicitly instead of falling back to its
+// reflection-based default.
+func (p Post) GetID() any    { return p.ID }
+func (p *Post) SetID(id any) { p.ID = id.(primitive.ObjectID) }
+
+// TagCount is the result of the $group-by-tag aggregation recipe.
+type TagCount struct {
+	Tag   string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
+
+// PostWithComments is a Post with its joined comments, as produced by
+// the $lookup aggregation recipe.
+type PostWithComments struct {
+	Post     `bson:",inline"`
+	Comments []bson.M `bson:"comments"`
+}
+
+// FacetPage is the combined result-page-plus-total-count shape produced
+// by a $facet stage.
+type FacetPage struct {
+	Results    []PostWithComments `bson:"results"`
+	TotalCount []struct {
+		Count int64 `bson:"count"`
+	} `bson:"totalCount"`
+}
+
+// ChangeEvent is the subset of a change stream event the recipe below
+// decodes fullDocument into.
+type ChangeEvent struct {
+	OperationType string `bson:"operationType"`
+	FullDocument  Post   `bson:"fullDocument"`
+}
+
+// resumeTokenFile is where the change-streams recipe persists its
+// resume token, so a restarted process resumes instead of replaying
+// from the beginning.
+const resumeTokenFile = "change_stream_resume_token.bson"
+
+func loadResumeToken() (bson.Raw, error) {
+	data, err := os.ReadFile(resumeTokenFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bson.Raw(data), nil
+}
+
+func saveResumeToken(token bson.Raw) error {
+	return os.WriteFile(resumeTokenFile, token, 0o600)
+}
+
 func main() {
 	// Initialising and connecting
 	// ========================================================================================
@@ -56,6 +120,11 @@ func main() {
 			"created_at": time.Now(),
 		})
 		if err != nil {
+			// a duplicate title, say from a unique index, surfaces as a
+			// typed error we can branch on instead of an opaque one
+			if index, keys, ok := mongoerr.DuplicateKey(mongoerr.Translate(err)); ok {
+				log.Fatalf("duplicate key in index %q: %v", index, keys)
+			}
 			log.Fatal(err)
 		}
 		fmt.Printf("inserted id: %s\n", res.InsertedID.(primitive.ObjectID).Hex())
@@ -122,8 +191,19 @@ func main() {
 
 		// update documents
 		res, err := col.UpdateMany(ctx, filter, update, options.Update().SetUpsert(true))
-		if err != nil {
-			log.Fatal(err)
+		if err := mongoerr.Translate(err); err != nil {
+			switch {
+			case errors.Is(err, mongoerr.ErrWriteConcernTimeout):
+				// the update was applied but not acknowledged in time;
+				// it's safe to retry since $set is idempotent
+				log.Fatal("write concern timeout, retry")
+			case errors.Is(err, mongoerr.ErrWriteConcernFailed):
+				// an unsatisfiable write concern or unreachable voters
+				// won't resolve itself by retrying the same request
+				log.Fatal("write concern failed: ", err)
+			default:
+				log.Fatal(err)
+			}
 		}
 		fmt.Printf("modified count: %d\n", res.ModifiedCount)
 		// => modified count: 17
@@ -211,6 +291,39 @@ func main() {
 		}
 	}
 
+	// Pagination
+	// ========================================================================================
+
+	{
+		// stable sort: created_at first, _id breaks ties between posts
+		// created in the same instant
+		sort := []mongopage.SortField{{Key: "created_at"}, {Key: "_id"}}
+
+		// first page: no token yet
+		page, err := mongopage.Paginate[Post](ctx, col, bson.M{}, sort, 2, "")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("page 1: %d posts\n", len(page.Items))
+
+		// fetch the next page using the opaque token from the previous one;
+		// no skip/limit, so this stays cheap no matter how deep we page
+		if page.NextPageToken != "" {
+			page2, err := mongopage.Paginate[Post](ctx, col, bson.M{}, sort, 2, page.NextPageToken)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("page 2: %d posts\n", len(page2.Items))
+
+			// walking PrevPageToken backwards lands us on page 1 again
+			back, err := mongopage.Paginate[Post](ctx, col, bson.M{}, sort, 2, page2.PrevPageToken)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("back to page 1: %d posts\n", len(back.Items))
+		}
+	}
+
 	// BulkWrite
 	// ========================================================================================
 
@@ -271,7 +384,11 @@ func main() {
 
 		// run bulk write
 		res, err := col.BulkWrite(ctx, writes)
-		if err != nil {
+		if err := mongoerr.Translate(err); err != nil {
+			if mongoerr.IsDuplicate(err) {
+				index, keys, _ := mongoerr.DuplicateKey(err)
+				log.Fatalf("duplicate key in index %q: %v", index, keys)
+			}
 			log.Fatal(err)
 		}
 		fmt.Printf(
@@ -282,4 +399,230 @@ func main() {
 		)
 		// => insert: 2, updated: 10, deleted: 3
 	}
+
+	// Transactions
+	// ========================================================================================
+
+	{
+		comments := client.Database("blog").Collection("comments")
+
+		// run the whole block as a single multi-document ACID transaction,
+		// retrying automatically on a transient transaction error
+		err := mongosession.Run(ctx, client, func(ctx context.Context) error {
+			// recover the session stashed on ctx so every call below
+			// participates in the same transaction without being passed
+			// a session argument explicitly
+			sessCtx, ok := mongosession.FromContext(ctx)
+			if !ok {
+				return fmt.Errorf("transactions: no session on context")
+			}
+
+			res, err := col.UpdateOne(sessCtx,
+				bson.M{"title": "Go mongodb driver cookbook"},
+				bson.M{"$inc": bson.M{"comments": 1}},
+			)
+			if err != nil {
+				// returning an error aborts the transaction
+				return err
+			}
+			if res.MatchedCount == 0 {
+				return mongo.ErrNoDocuments
+			}
+
+			_, err = comments.InsertOne(sessCtx, bson.M{
+				"post_title": "Go mongodb driver cookbook",
+				"body":       "nice cookbook!",
+				"created_at": time.Now(),
+			})
+			return err
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("transaction committed")
+		// => transaction committed
+	}
+
+	// Repository[T]
+	// ========================================================================================
+
+	{
+		// wrap the collection once; every call below works with a Post
+		// directly instead of bson.M
+		posts := mongorepo.NewRepository[Post](col)
+
+		// InsertOne: the repository generates a new _id for the Post and
+		// writes it back onto the Post for us
+		post := Post{
+			Title:     "Generics in Go",
+			Tags:      []string{"golang", "generics"},
+			Body:      "a typed repository over *mongo.Collection",
+			CreatedAt: time.Now(),
+		}
+		if err := posts.InsertOne(ctx, &post); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("inserted id: %s\n", post.ID.Hex())
+
+		// FindByID
+		found, err := posts.FindByID(ctx, post.ID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("post: %+v\n", *found)
+
+		// Find
+		golangPosts, err := posts.Find(ctx, bson.M{"tags": bson.M{"$elemMatch": bson.M{"$eq": "golang"}}})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("found %d posts tagged golang\n", len(golangPosts))
+
+		// UpdateByID
+		if err := posts.UpdateByID(ctx, post.ID, bson.M{"$inc": bson.M{"comments": 1}}); err != nil {
+			log.Fatal(err)
+		}
+
+		// ReplaceByID: upsert-aware, so this also works for a Post whose
+		// _id doesn't exist in the collection yet
+		found.Title = "Generics in Go (updated)"
+		if err := posts.ReplaceByID(ctx, found); err != nil {
+			log.Fatal(err)
+		}
+
+		// Count
+		n, err := posts.Count(ctx, bson.M{"tags": bson.M{"$elemMatch": bson.M{"$eq": "golang"}}})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("golang posts: %d\n", n)
+
+		// DeleteByID
+		if err := posts.DeleteByID(ctx, post.ID); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Templated queries
+	// ========================================================================================
+
+	{
+		// the "posts tagged golang" filter from the FindOne recipe above,
+		// now a named query anyone can invoke without touching bson.M
+		docs, err := mongoquery.Run(ctx, col, "posts_by_tag", map[string]any{"tag": "golang"})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("found %d posts tagged golang\n", len(docs))
+
+		// omitting "tag" compiles the clause out entirely instead of
+		// matching on an empty string
+		all, err := mongoquery.Run(ctx, col, "posts_by_tag", map[string]any{})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("found %d posts total\n", len(all))
+
+		// "before" renders as extended JSON's {"$date": ...} so it
+		// compares against created_at's actual BSON Date type instead
+		// of a string
+		recent, err := mongoquery.Run(ctx, col, "posts_by_tag", map[string]any{"before": time.Now()})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("found %d posts created before now\n", len(recent))
+	}
+
+	// Aggregation
+	// ========================================================================================
+
+	{
+		// $match posts tagged golang, $unwind their tags, $group to count
+		// how many posts carry each one
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"tags": bson.M{"$elemMatch": bson.M{"$eq": "golang"}}}}},
+			{{Key: "$unwind", Value: "$tags"}},
+			{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		}
+		cursor, err := col.Aggregate(ctx, pipeline)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var tagCounts []TagCount
+		if err := cursor.All(ctx, &tagCounts); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("tag counts: %+v\n", tagCounts)
+
+		// $lookup each post's comments, then $facet a page of results
+		// alongside the total count in a single round trip
+		pipeline = mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"tags": bson.M{"$elemMatch": bson.M{"$eq": "golang"}}}}},
+			{{Key: "$lookup", Value: bson.M{
+				"from":         "comments",
+				"localField":   "title",
+				"foreignField": "post_title",
+				"as":           "comments",
+			}}},
+			{{Key: "$facet", Value: bson.M{
+				"results":    mongo.Pipeline{{{Key: "$limit", Value: 10}}},
+				"totalCount": mongo.Pipeline{{{Key: "$count", Value: "count"}}},
+			}}},
+		}
+		cursor, err = col.Aggregate(ctx, pipeline)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var page []FacetPage
+		if err := cursor.All(ctx, &page); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("page: %+v\n", page)
+	}
+
+	// Change streams
+	// ========================================================================================
+
+	{
+		// stop watching on Ctrl+C instead of on the 10s timeout ctx above,
+		// so the loop below can drain and persist its last resume token
+		watchCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update"}}}}},
+		}
+		watchOpts := options.ChangeStream()
+		if token, err := loadResumeToken(); err != nil {
+			log.Fatal(err)
+		} else if token != nil {
+			watchOpts.SetResumeAfter(token)
+		}
+
+		stream, err := col.Watch(watchCtx, pipeline, watchOpts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer stream.Close(watchCtx)
+
+		for stream.Next(watchCtx) {
+			var event ChangeEvent
+			if err := stream.Decode(&event); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("%s: %+v\n", event.OperationType, event.FullDocument)
+
+			// persisting the resume token after each event means a
+			// restart picks up right where we left off instead of
+			// replaying (or missing) changes
+			if err := saveResumeToken(stream.ResumeToken()); err != nil {
+				log.Fatal(err)
+			}
+		}
+		// stream.Err reports a real failure; ctx cancellation from the
+		// Ctrl+C above is an expected, graceful exit
+		if err := stream.Err(); err != nil && watchCtx.Err() == nil {
+			log.Fatal(err)
+		}
+	}
 }
@@ -0,0 +1,181 @@
+// Package mongoerr translates the driver's opaque errors into typed
+// sentinels callers can branch on with errors.Is/errors.As, instead of
+// inspecting *mongo.WriteException or parsing server messages at every
+// call site.
+package mongoerr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// duplicateKeyCode is the server error code for a unique index
+// violation (the "E11000" you see in the server log).
+const duplicateKeyCode = 11000
+
+var (
+	// ErrNotFound is returned in place of mongo.ErrNoDocuments.
+	ErrNotFound = errors.New("mongoerr: document not found")
+
+	// ErrDuplicateKey is returned for a unique index violation. Use
+	// DuplicateKey to recover which index and key values conflicted.
+	ErrDuplicateKey = errors.New("mongoerr: duplicate key")
+
+	// ErrDecode is returned for a BSON decode mismatch between a document
+	// and the Go type it was decoded into. The returned error carries the
+	// decode error's message, not the decode error itself.
+	ErrDecode = errors.New("mongoerr: decode mismatch")
+
+	// ErrWriteConcernTimeout is returned when the requested write
+	// concern couldn't be satisfied in time (maxTimeMS expired). The
+	// write itself still happened; only acknowledgement timed out.
+	ErrWriteConcernTimeout = errors.New("mongoerr: write concern timeout")
+
+	// ErrWriteConcernFailed is returned for any other write concern
+	// error, e.g. an unsatisfiable write concern or unreachable voting
+	// members. Unlike ErrWriteConcernTimeout it isn't necessarily safe
+	// to just wait and retry.
+	ErrWriteConcernFailed = errors.New("mongoerr: write concern error")
+
+	// ErrTransient is returned for an error labelled
+	// TransientTransactionError, meaning the whole transaction is safe
+	// to retry.
+	ErrTransient = errors.New("mongoerr: transient transaction error")
+)
+
+// DuplicateKeyError carries the index name and key values the server
+// reported for a unique index violation. Its Is method matches
+// ErrDuplicateKey, so errors.Is(err, ErrDuplicateKey) still matches.
+type DuplicateKeyError struct {
+	Index string
+	Keys  bson.M
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("mongoerr: duplicate key in index %q: %v", e.Index, e.Keys)
+}
+
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+// Translate maps err onto the sentinels above, leaving it untouched if
+// none apply.
+func Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+
+	var decErr *bsoncodec.DecodeError
+	if errors.As(err, &decErr) {
+		return fmt.Errorf("%w: %s", ErrDecode, decErr.Error())
+	}
+
+	var srvErr mongo.ServerError
+	if errors.As(err, &srvErr) && srvErr.HasErrorLabel("TransientTransactionError") {
+		return ErrTransient
+	}
+
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		if dupErr := duplicateFromErrors(we.WriteErrors); dupErr != nil {
+			return dupErr
+		}
+		if we.WriteConcernError != nil {
+			return writeConcernErr(*we.WriteConcernError)
+		}
+	}
+
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		werrs := make([]mongo.WriteError, len(bwe.WriteErrors))
+		for i, e := range bwe.WriteErrors {
+			werrs[i] = e.WriteError
+		}
+		if dupErr := duplicateFromErrors(werrs); dupErr != nil {
+			return dupErr
+		}
+		if bwe.WriteConcernError != nil {
+			return writeConcernErr(*bwe.WriteConcernError)
+		}
+	}
+
+	return err
+}
+
+// writeConcernErr maps a write concern error to ErrWriteConcernTimeout
+// only when it's an actual maxTimeMS expiry (code 50); anything else
+// (an unsatisfiable write concern, unreachable voting members, ...)
+// maps to the more general ErrWriteConcernFailed instead.
+func writeConcernErr(wce mongo.WriteConcernError) error {
+	if wce.IsMaxTimeMSExpiredError() {
+		return ErrWriteConcernTimeout
+	}
+	return ErrWriteConcernFailed
+}
+
+// IsDuplicate reports whether err is (or wraps) a unique index
+// violation.
+func IsDuplicate(err error) bool {
+	return errors.Is(err, ErrDuplicateKey)
+}
+
+// DuplicateKey recovers the conflicting index name and key values from
+// err, if it's a duplicate key error.
+func DuplicateKey(err error) (index string, keys bson.M, ok bool) {
+	var dupErr *DuplicateKeyError
+	if errors.As(err, &dupErr) {
+		return dupErr.Index, dupErr.Keys, true
+	}
+	return "", nil, false
+}
+
+func duplicateFromErrors(errs []mongo.WriteError) *DuplicateKeyError {
+	for _, werr := range errs {
+		if werr.Code == duplicateKeyCode {
+			index, keys := parseDuplicateMessage(werr.Message)
+			return &DuplicateKeyError{Index: index, Keys: keys}
+		}
+	}
+	return nil
+}
+
+// dupKeyPattern matches the server's "index: <name> dup key: { ... }"
+// suffix of an E11000 message.
+var dupKeyPattern = regexp.MustCompile(`index:\s*(\S+)\s+dup key:\s*\{(.*)\}`)
+
+// dupKeyFieldPattern matches one "field: value" pair inside the braces
+// captured by dupKeyPattern.
+var dupKeyFieldPattern = regexp.MustCompile(`(\w+):\s*("(?:[^"\\]|\\.)*"|[^,}]+)`)
+
+// parseDuplicateMessage pulls the index name and key values out of a
+// duplicate key server message. It's best-effort: messages that don't
+// match the expected shape yield an empty index and nil keys rather
+// than an error.
+func parseDuplicateMessage(msg string) (index string, keys bson.M) {
+	m := dupKeyPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return "", nil
+	}
+	index = m[1]
+	keys = bson.M{}
+	for _, fm := range dupKeyFieldPattern.FindAllStringSubmatch(m[2], -1) {
+		key, val := fm[1], strings.TrimSpace(fm[2])
+		if unquoted, err := strconv.Unquote(val); err == nil {
+			val = unquoted
+		}
+		keys[key] = val
+	}
+	return index, keys
+}
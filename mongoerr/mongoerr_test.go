@@ -0,0 +1,120 @@
+package mongoerr
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestParseDuplicateMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       string
+		wantIndex string
+		wantKeys  bson.M
+	}{
+		{
+			name:      "single string field",
+			msg:       `E11000 duplicate key error collection: db.posts index: slug_1 dup key: { slug: "hello-world" }`,
+			wantIndex: "slug_1",
+			wantKeys:  bson.M{"slug": "hello-world"},
+		},
+		{
+			name:      "compound index, two fields",
+			msg:       `E11000 duplicate key error collection: db.posts index: author_1_slug_1 dup key: { author: "jane", slug: "hello-world" }`,
+			wantIndex: "author_1_slug_1",
+			wantKeys:  bson.M{"author": "jane", "slug": "hello-world"},
+		},
+		{
+			name:      "unquoted numeric value",
+			msg:       `E11000 duplicate key error collection: db.posts index: rank_1 dup key: { rank: 7 }`,
+			wantIndex: "rank_1",
+			wantKeys:  bson.M{"rank": "7"},
+		},
+		{
+			name:      "message doesn't match the expected shape",
+			msg:       "some unrelated server error",
+			wantIndex: "",
+			wantKeys:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, keys := parseDuplicateMessage(tt.msg)
+			if index != tt.wantIndex {
+				t.Errorf("index = %q, want %q", index, tt.wantIndex)
+			}
+			if len(keys) != len(tt.wantKeys) {
+				t.Fatalf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+			for k, v := range tt.wantKeys {
+				if keys[k] != v {
+					t.Errorf("keys[%q] = %v, want %v", k, keys[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateDuplicateKey(t *testing.T) {
+	err := mongo.WriteException{
+		WriteErrors: mongo.WriteErrors{
+			{Code: duplicateKeyCode, Message: `E11000 duplicate key error collection: db.posts index: slug_1 dup key: { slug: "hello-world" }`},
+		},
+	}
+
+	got := Translate(err)
+	if !errors.Is(got, ErrDuplicateKey) {
+		t.Fatalf("Translate(%v) = %v, want it to match ErrDuplicateKey", err, got)
+	}
+
+	index, keys, ok := DuplicateKey(got)
+	if !ok {
+		t.Fatal("DuplicateKey: ok = false, want true")
+	}
+	if index != "slug_1" || keys["slug"] != "hello-world" {
+		t.Errorf("DuplicateKey = (%q, %v), want (%q, %v)", index, keys, "slug_1", bson.M{"slug": "hello-world"})
+	}
+}
+
+func TestTranslateWriteConcernError(t *testing.T) {
+	tests := []struct {
+		name string
+		wce  mongo.WriteConcernError
+		want error
+	}{
+		{
+			name: "maxTimeMS expiry maps to the timeout sentinel",
+			wce:  mongo.WriteConcernError{Code: 50, Name: "MaxTimeMSExpired"},
+			want: ErrWriteConcernTimeout,
+		},
+		{
+			name: "anything else maps to the general failure sentinel",
+			wce:  mongo.WriteConcernError{Code: 100, Name: "UnsatisfiableWriteConcern"},
+			want: ErrWriteConcernFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mongo.WriteException{WriteConcernError: &tt.wce}
+			got := Translate(err)
+			if !errors.Is(got, tt.want) {
+				t.Errorf("Translate(%v) = %v, want it to match %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateLeavesOtherErrorsUntouched(t *testing.T) {
+	err := errors.New("boom")
+	if got := Translate(err); got != err {
+		t.Errorf("Translate(%v) = %v, want it returned unchanged", err, got)
+	}
+	if Translate(nil) != nil {
+		t.Error("Translate(nil) should return nil")
+	}
+}
@@ -0,0 +1,161 @@
+// Package mongopage implements cursor-based pagination with opaque page
+// tokens, so callers resume exactly after (or before) the last document
+// they saw instead of paying the cost of a growing skip/limit.
+package mongopage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SortField is one field of a multi-field sort. The last field should
+// be unique across the collection (typically _id) so paging never skips
+// or repeats a document; Paginate appends _id automatically if it's
+// missing.
+type SortField struct {
+	Key  string
+	Desc bool
+}
+
+// Page is one page of results plus the tokens needed to fetch the
+// pages immediately after and before it. An empty token means there is
+// no such page.
+type Page[T any] struct {
+	Items         []T
+	NextPageToken string
+	PrevPageToken string
+}
+
+// Paginate returns the page of documents matching filter starting
+// right after token (or, for a PrevPageToken, ending right before it),
+// ordered by sort. Pass an empty token for the first page.
+func Paginate[T any](ctx context.Context, col *mongo.Collection, filter bson.M, sort []SortField, pageSize int64, token string) (*Page[T], error) {
+	sort = withTiebreaker(sort)
+
+	var tok pageToken
+	if token != "" {
+		var err error
+		tok, err = decodeToken(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	q := filter
+	if token != "" {
+		q = bson.M{"$and": []bson.M{filter, rangeFilter(sort, tok)}}
+	}
+
+	cursor, err := col.Find(ctx, q, options.Find().
+		SetSort(sortDoc(sort, tok.Reverse)).
+		SetLimit(pageSize+1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var raws []bson.Raw
+	if err := cursor.All(ctx, &raws); err != nil {
+		return nil, err
+	}
+
+	hasMore := int64(len(raws)) > pageSize
+	if hasMore {
+		raws = raws[:pageSize]
+	}
+	if tok.Reverse {
+		// raws were fetched in the reverse sort order to land on the
+		// nearest preceding documents; flip back to display order.
+		for i, j := 0, len(raws)-1; i < j; i, j = i+1, j-1 {
+			raws[i], raws[j] = raws[j], raws[i]
+		}
+	}
+
+	page := &Page[T]{Items: make([]T, len(raws))}
+	for i, raw := range raws {
+		if err := bson.Unmarshal(raw, &page.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	hasNext := hasMore
+	hasPrev := token != ""
+	if tok.Reverse {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	if len(raws) > 0 && hasNext {
+		last := raws[len(raws)-1]
+		page.NextPageToken, err = buildToken(sort, last, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(raws) > 0 && hasPrev {
+		first := raws[0]
+		page.PrevPageToken, err = buildToken(sort, first, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func buildToken(sort []SortField, doc bson.Raw, reverse bool) (string, error) {
+	return pageToken{Reverse: reverse, Values: boundaryValues(sort, doc)}.encode()
+}
+
+func withTiebreaker(sort []SortField) []SortField {
+	for _, f := range sort {
+		if f.Key == "_id" {
+			return sort
+		}
+	}
+	return append(sort, SortField{Key: "_id"})
+}
+
+// sortDoc builds the $sort document used to fetch a page. reverse flips
+// every direction, which is how a prev-page token walks backwards
+// efficiently using the same indexes as the forward sort.
+func sortDoc(sort []SortField, reverse bool) bson.D {
+	d := make(bson.D, len(sort))
+	for i, f := range sort {
+		dir := 1
+		if f.Desc {
+			dir = -1
+		}
+		if reverse {
+			dir = -dir
+		}
+		d[i] = bson.E{Key: f.Key, Value: dir}
+	}
+	return d
+}
+
+// rangeFilter builds the {$or: [...]} condition that resumes strictly
+// after (or, for a reverse token, strictly before) the boundary values
+// in tok, per field of sort.
+func rangeFilter(sort []SortField, tok pageToken) bson.M {
+	or := make([]bson.M, len(sort))
+	for i, f := range sort {
+		cond := bson.M{}
+		for j := 0; j < i; j++ {
+			cond[sort[j].Key] = tok.Values[j]
+		}
+		desc := f.Desc
+		if tok.Reverse {
+			desc = !desc
+		}
+		op := "$gt"
+		if desc {
+			op = "$lt"
+		}
+		cond[f.Key] = bson.M{op: tok.Values[i]}
+		or[i] = cond
+	}
+	return bson.M{"$or": or}
+}
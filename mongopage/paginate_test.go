@@ -0,0 +1,92 @@
+package mongopage
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWithTiebreaker(t *testing.T) {
+	got := withTiebreaker([]SortField{{Key: "created_at", Desc: true}})
+	want := []SortField{{Key: "created_at", Desc: true}, {Key: "_id"}}
+	if len(got) != len(want) || got[len(got)-1].Key != "_id" {
+		t.Errorf("withTiebreaker = %v, want it to append an _id tiebreaker", got)
+	}
+
+	// already present: left untouched, no duplicate appended.
+	got = withTiebreaker([]SortField{{Key: "_id", Desc: true}})
+	if len(got) != 1 {
+		t.Errorf("withTiebreaker appended a duplicate _id: %v", got)
+	}
+}
+
+func TestSortDoc(t *testing.T) {
+	sort := []SortField{{Key: "created_at", Desc: true}, {Key: "_id"}}
+
+	got := sortDoc(sort, false)
+	want := bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}
+	if !sortDocEqual(got, want) {
+		t.Errorf("sortDoc(forward) = %v, want %v", got, want)
+	}
+
+	got = sortDoc(sort, true)
+	want = bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: -1}}
+	if !sortDocEqual(got, want) {
+		t.Errorf("sortDoc(reverse) = %v, want %v", got, want)
+	}
+}
+
+func sortDocEqual(a, b bson.D) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key || a[i].Value != b[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRangeFilter(t *testing.T) {
+	sort := []SortField{{Key: "created_at", Desc: true}, {Key: "_id"}}
+	tok := pageToken{Values: []bson.RawValue{
+		mustRawValue(t, int64(100)),
+		mustRawValue(t, "abc"),
+	}}
+
+	got := rangeFilter(sort, tok)
+	or, ok := got["$or"].([]bson.M)
+	if !ok || len(or) != 2 {
+		t.Fatalf("rangeFilter = %v, want a 2-clause $or", got)
+	}
+
+	// created_at is Desc, so resuming forward means strictly less than
+	// the boundary value.
+	createdClause := or[0]["created_at"].(bson.M)
+	if _, ok := createdClause["$lt"]; !ok {
+		t.Errorf("or[0][created_at] = %v, want a $lt clause (Desc field)", createdClause)
+	}
+
+	// _id is ascending and only applies once created_at ties, so it's
+	// gated on equality of the higher-precedence field.
+	if eq, ok := or[1]["created_at"].(bson.RawValue); !ok || !eq.Equal(tok.Values[0]) {
+		t.Errorf("or[1][created_at] = %v, want the tiebreak equality %v", or[1]["created_at"], tok.Values[0])
+	}
+	idClause := or[1]["_id"].(bson.M)
+	if _, ok := idClause["$gt"]; !ok {
+		t.Errorf("or[1][_id] = %v, want a $gt clause (ascending field)", idClause)
+	}
+}
+
+func TestRangeFilterReverseFlipsDirection(t *testing.T) {
+	sort := []SortField{{Key: "_id"}}
+	tok := pageToken{Reverse: true, Values: []bson.RawValue{mustRawValue(t, "abc")}}
+
+	got := rangeFilter(sort, tok)
+	or := got["$or"].([]bson.M)
+	idClause := or[0]["_id"].(bson.M)
+	if _, ok := idClause["$lt"]; !ok {
+		t.Errorf("reverse token on an ascending field should flip to $lt, got %v", idClause)
+	}
+}
@@ -0,0 +1,48 @@
+package mongopage
+
+import (
+	"encoding/base64"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// pageToken is the opaque state carried between pages: the sort-key
+// values of the boundary document (the last one shown, for a next
+// token; the first one shown, for a prev token) and which direction to
+// resume in.
+type pageToken struct {
+	Reverse bool            `bson:"r"`
+	Values  []bson.RawValue `bson:"v"`
+}
+
+// encode serialises t as the opaque string handed back to callers as
+// NextPageToken/PrevPageToken.
+func (t pageToken) encode() (string, error) {
+	raw, err := bson.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeToken reverses encode. Callers treat the token as opaque; it is
+// only ever produced by this package.
+func decodeToken(s string) (pageToken, error) {
+	var t pageToken
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, err
+	}
+	err = bson.Unmarshal(raw, &t)
+	return t, err
+}
+
+// boundaryValues extracts, in sort order, the value of each sort key
+// from doc, for use as the boundary of a page token.
+func boundaryValues(sort []SortField, doc bson.Raw) []bson.RawValue {
+	values := make([]bson.RawValue, len(sort))
+	for i, f := range sort {
+		values[i] = doc.Lookup(f.Key)
+	}
+	return values
+}
@@ -0,0 +1,90 @@
+package mongopage
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// mustRawValue wraps v in a one-field document and looks the field back
+// out as a bson.RawValue, the same shape boundaryValues produces from a
+// real document.
+func mustRawValue(t *testing.T, v any) bson.RawValue {
+	t.Helper()
+	data, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		t.Fatalf("Marshal(%v): %v", v, err)
+	}
+	return bson.Raw(data).Lookup("v")
+}
+
+func TestPageTokenEncodeDecodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		reverse bool
+		values  []any
+	}{
+		{name: "forward, single string key", reverse: false, values: []any{"golang"}},
+		{name: "reverse, single int key", reverse: true, values: []any{int64(42)}},
+		{name: "forward, multi-field key", reverse: false, values: []any{"golang", int64(42)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := make([]bson.RawValue, len(tt.values))
+			for i, v := range tt.values {
+				values[i] = mustRawValue(t, v)
+			}
+			want := pageToken{Reverse: tt.reverse, Values: values}
+
+			encoded, err := want.encode()
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if encoded == "" {
+				t.Fatal("encode returned an empty string")
+			}
+
+			got, err := decodeToken(encoded)
+			if err != nil {
+				t.Fatalf("decodeToken: %v", err)
+			}
+			if got.Reverse != want.Reverse {
+				t.Errorf("Reverse = %v, want %v", got.Reverse, want.Reverse)
+			}
+			if len(got.Values) != len(want.Values) {
+				t.Fatalf("len(Values) = %d, want %d", len(got.Values), len(want.Values))
+			}
+			for i := range want.Values {
+				if !got.Values[i].Equal(want.Values[i]) {
+					t.Errorf("Values[%d] = %v, want %v", i, got.Values[i], want.Values[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeTokenRejectsGarbage(t *testing.T) {
+	if _, err := decodeToken("not-a-valid-token!!"); err == nil {
+		t.Error("expected an error decoding a malformed token, got nil")
+	}
+}
+
+func TestBoundaryValues(t *testing.T) {
+	doc, err := bson.Marshal(bson.M{"tag": "golang", "created_at": int64(100), "_id": "abc"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	sort := []SortField{{Key: "tag"}, {Key: "created_at", Desc: true}, {Key: "_id"}}
+	values := boundaryValues(sort, bson.Raw(doc))
+	if len(values) != 3 {
+		t.Fatalf("len(values) = %d, want 3", len(values))
+	}
+	if got := values[0].StringValue(); got != "golang" {
+		t.Errorf("values[0] = %q, want %q", got, "golang")
+	}
+	if got := values[2].StringValue(); got != "abc" {
+		t.Errorf("values[2] = %q, want %q", got, "abc")
+	}
+}
@@ -0,0 +1,240 @@
+// Package mongoquery loads named MongoDB queries from a directory of
+// text/template files and executes them with caller-supplied params,
+// so request-time filters live as a declarative layer above the
+// driver instead of being assembled by hand in every call site.
+//
+// Each template's first line declares which driver call it dispatches
+// to, e.g. "// op: find". The rest of the file is the query body: a
+// JSON filter document for "find"/"findone", or a JSON pipeline array
+// for "aggregate".
+package mongoquery
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// templateFuncs are the functions every query template is compiled
+// with. "json" is the only safe way to splice a caller-supplied param
+// into a filter: it renders the value as an escaped JSON literal, so a
+// param can never break out of its surrounding quotes and inject
+// arbitrary BSON operators. "epochMillis" renders a time.Time's Unix
+// milliseconds as the quoted decimal string that goes inside the
+// canonical extended-JSON {"$date": {"$numberLong": ...}} form
+// UnmarshalExtJSON parses, so a templated clause compares
+// against created_at's actual BSON Date type instead of a string that
+// can never match it.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	},
+	"epochMillis": func(t time.Time) (string, error) {
+		b, err := json.Marshal(strconv.FormatInt(t.UnixMilli(), 10))
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	},
+}
+
+//go:embed queries/*.tmpl
+var queryFiles embed.FS
+
+// op names the driver call a query dispatches to.
+type op string
+
+const (
+	opFind      op = "find"
+	opFindOne   op = "findone"
+	opAggregate op = "aggregate"
+)
+
+type compiledQuery struct {
+	op   op
+	tmpl *template.Template
+}
+
+// queries is the package-level map of every query parsed once at
+// init time, keyed by file name without its .json.tmpl suffix.
+var queries map[string]*compiledQuery
+
+func init() {
+	entries, err := queryFiles.ReadDir("queries")
+	if err != nil {
+		panic(err)
+	}
+
+	queries = make(map[string]*compiledQuery, len(entries))
+	for _, entry := range entries {
+		raw, err := queryFiles.ReadFile("queries/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+
+		o, body, err := splitFrontMatter(raw)
+		if err != nil {
+			panic(fmt.Sprintf("mongoquery: %s: %s", entry.Name(), err))
+		}
+
+		tmpl, err := template.New(entry.Name()).Funcs(templateFuncs).Parse(body)
+		if err != nil {
+			panic(fmt.Sprintf("mongoquery: %s: %s", entry.Name(), err))
+		}
+
+		name := strings.TrimSuffix(strings.TrimSuffix(entry.Name(), ".tmpl"), ".json")
+		queries[name] = &compiledQuery{op: o, tmpl: tmpl}
+	}
+}
+
+// splitFrontMatter pulls the "// op: <name>" directive off the first
+// line of a template file and returns it alongside the remaining body.
+func splitFrontMatter(raw []byte) (op, string, error) {
+	line, body, ok := strings.Cut(string(raw), "\n")
+	if !ok {
+		return "", "", errors.New(`missing "// op: <find|findone|aggregate>" front matter`)
+	}
+
+	const prefix = "// op:"
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, prefix) {
+		return "", "", errors.New(`first line must declare "// op: <find|findone|aggregate>"`)
+	}
+	return op(strings.TrimSpace(strings.TrimPrefix(line, prefix))), body, nil
+}
+
+// render executes the query's template with params, returning its raw
+// JSON text. Templates render an omitted optional clause as a bare "{}"
+// wherever it appears; stripEmptyClauses, applied after the text is
+// parsed, removes those once they're structured data instead of
+// substrings, so a clause can live inside a $and/$or array without
+// risking invalid JSON. Every param a template splices into the filter
+// must go through the "json" template func, never bare "{{.field}}"
+// interpolation, or a value like `x", "$where": "1==1` would inject
+// its own BSON operators into the document.
+func (q *compiledQuery) render(params map[string]any) (string, error) {
+	var buf bytes.Buffer
+	if err := q.tmpl.Execute(&buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// stripEmptyClauses walks doc, dropping empty sub-documents out of any
+// array and, if that empties a $and/$or/$nor array entirely, dropping
+// the operator itself rather than leaving Mongo a nonsensical empty
+// one.
+func stripEmptyClauses(doc bson.M) bson.M {
+	for key, value := range doc {
+		switch v := value.(type) {
+		case bson.M:
+			doc[key] = stripEmptyClauses(v)
+		case primitive.A:
+			kept := v[:0]
+			for _, elem := range v {
+				if m, ok := elem.(bson.M); ok {
+					m = stripEmptyClauses(m)
+					if len(m) == 0 {
+						continue
+					}
+					elem = m
+				}
+				kept = append(kept, elem)
+			}
+			if len(kept) == 0 && (key == "$and" || key == "$or" || key == "$nor") {
+				delete(doc, key)
+				continue
+			}
+			doc[key] = kept
+		}
+	}
+	return doc
+}
+
+// Run renders the named query with params and dispatches it to
+// Find, FindOne or Aggregate on col according to its front-matter
+// directive, returning every matching document. A "findone" query
+// returns at most one document.
+func Run(ctx context.Context, col *mongo.Collection, name string, params map[string]any) ([]bson.M, error) {
+	q, ok := queries[name]
+	if !ok {
+		return nil, fmt.Errorf("mongoquery: unknown query %q", name)
+	}
+
+	text, err := q.render(params)
+	if err != nil {
+		return nil, err
+	}
+
+	switch q.op {
+	case opFindOne:
+		var doc bson.M
+		if err := bson.UnmarshalExtJSON([]byte(text), true, &doc); err != nil {
+			return nil, err
+		}
+		doc = stripEmptyClauses(doc)
+		var result bson.M
+		err := col.FindOne(ctx, doc).Decode(&result)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []bson.M{result}, nil
+
+	case opFind:
+		var filter bson.M
+		if err := bson.UnmarshalExtJSON([]byte(text), true, &filter); err != nil {
+			return nil, err
+		}
+		filter = stripEmptyClauses(filter)
+		cursor, err := col.Find(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, err
+		}
+		return docs, nil
+
+	case opAggregate:
+		var pipeline []bson.M
+		if err := bson.UnmarshalExtJSON([]byte(text), true, &pipeline); err != nil {
+			return nil, err
+		}
+		for i, stage := range pipeline {
+			pipeline[i] = stripEmptyClauses(stage)
+		}
+		cursor, err := col.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, err
+		}
+		return docs, nil
+
+	default:
+		return nil, fmt.Errorf("mongoquery: query %q declares unknown op %q", name, q.op)
+	}
+}
@@ -0,0 +1,154 @@
+package mongoquery
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestStripEmptyClauses(t *testing.T) {
+	tests := []struct {
+		name string
+		in   bson.M
+		want bson.M
+	}{
+		{
+			name: "no empty clauses",
+			in:   bson.M{"$and": primitive.A{bson.M{"tag": "go"}, bson.M{"n": 1}}},
+			want: bson.M{"$and": primitive.A{bson.M{"tag": "go"}, bson.M{"n": 1}}},
+		},
+		{
+			name: "drops empty sub-document from array",
+			in:   bson.M{"$and": primitive.A{bson.M{"tag": "go"}, bson.M{}}},
+			want: bson.M{"$and": primitive.A{bson.M{"tag": "go"}}},
+		},
+		{
+			name: "drops operator once its array is empty",
+			in:   bson.M{"$and": primitive.A{bson.M{}, bson.M{}}},
+			want: bson.M{},
+		},
+		{
+			name: "drops empty docs from a non-operator array without deleting the key",
+			in:   bson.M{"items": primitive.A{bson.M{}}},
+			want: bson.M{"items": primitive.A{}},
+		},
+		{
+			name: "recurses into nested sub-documents",
+			in:   bson.M{"$or": primitive.A{bson.M{"$and": primitive.A{bson.M{}}}, bson.M{"tag": "go"}}},
+			want: bson.M{"$or": primitive.A{bson.M{"tag": "go"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripEmptyClauses(tt.in)
+			gb, err := bson.MarshalExtJSON(got, true, false)
+			if err != nil {
+				t.Fatalf("marshal got: %v", err)
+			}
+			wb, err := bson.MarshalExtJSON(tt.want, true, false)
+			if err != nil {
+				t.Fatalf("marshal want: %v", err)
+			}
+			if string(gb) != string(wb) {
+				t.Errorf("stripEmptyClauses(%v) = %s, want %s", tt.in, gb, wb)
+			}
+		})
+	}
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	o, body, err := splitFrontMatter([]byte("// op: find\n{}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o != opFind {
+		t.Errorf("op = %q, want %q", o, opFind)
+	}
+	if body != "{}\n" {
+		t.Errorf("body = %q, want %q", body, "{}\n")
+	}
+
+	if _, _, err := splitFrontMatter([]byte("{}\n")); err == nil {
+		t.Error("expected error for missing front matter, got nil")
+	}
+}
+
+// TestRenderEscapesParams reproduces the posts_by_tag injection: a tag
+// value crafted to close the surrounding quotes and splice in its own
+// operator must render as a harmless literal string, not as BSON.
+func TestRenderEscapesParams(t *testing.T) {
+	q, ok := queries["posts_by_tag"]
+	if !ok {
+		t.Fatal(`query "posts_by_tag" not found`)
+	}
+
+	const payload = `golang", "$where": "1==1`
+	text, err := q.render(map[string]any{"tag": payload})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var filter bson.M
+	if err := bson.UnmarshalExtJSON([]byte(text), true, &filter); err != nil {
+		t.Fatalf("rendered filter is not valid JSON: %v\n%s", err, text)
+	}
+	filter = stripEmptyClauses(filter)
+
+	and, _ := filter["$and"].(primitive.A)
+	if len(and) == 0 {
+		t.Fatalf("expected a $and clause, got %v", filter)
+	}
+	tagClause, ok := and[0].(bson.M)
+	if !ok {
+		t.Fatalf("expected tags clause, got %v", and[0])
+	}
+	elemMatch, _ := tagClause["tags"].(bson.M)["$elemMatch"].(bson.M)
+	if got := elemMatch["$eq"]; got != payload {
+		t.Errorf("$eq = %v, want the literal payload %q", got, payload)
+	}
+	if _, found := elemMatch["$where"]; found {
+		t.Error("payload injected a $where operator into the filter")
+	}
+}
+
+// TestRenderBeforeIsARealDate guards against the clause comparing a BSON
+// Date to a JSON string, which by BSON type ordering can never match:
+// "before" must parse back out as a primitive.DateTime.
+func TestRenderBeforeIsARealDate(t *testing.T) {
+	q, ok := queries["posts_by_tag"]
+	if !ok {
+		t.Fatal(`query "posts_by_tag" not found`)
+	}
+
+	before := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	text, err := q.render(map[string]any{"before": before})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+
+	var filter bson.M
+	if err := bson.UnmarshalExtJSON([]byte(text), true, &filter); err != nil {
+		t.Fatalf("rendered filter is not valid JSON: %v\n%s", err, text)
+	}
+	filter = stripEmptyClauses(filter)
+
+	and, _ := filter["$and"].(primitive.A)
+	if len(and) == 0 {
+		t.Fatalf("expected a $and clause, got %v", filter)
+	}
+	dateClause, ok := and[len(and)-1].(bson.M)
+	if !ok {
+		t.Fatalf("expected created_at clause, got %v", and[len(and)-1])
+	}
+	lt := dateClause["created_at"].(bson.M)["$lt"]
+	dt, ok := lt.(primitive.DateTime)
+	if !ok {
+		t.Fatalf("created_at $lt = %T(%v), want primitive.DateTime", lt, lt)
+	}
+	if !dt.Time().Equal(before) {
+		t.Errorf("created_at $lt = %v, want %v", dt.Time(), before)
+	}
+}
@@ -0,0 +1,38 @@
+package mongorepo
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrNotFound is returned when a lookup or update targets a document
+	// that doesn't exist.
+	ErrNotFound = errors.New("mongorepo: document not found")
+
+	// ErrDuplicate is returned when an insert or replace violates a
+	// unique index.
+	ErrDuplicate = errors.New("mongorepo: duplicate key")
+)
+
+// translateErr maps the handful of driver errors Repository callers
+// routinely need to branch on into the sentinels above, leaving
+// everything else untouched.
+func translateErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, werr := range we.WriteErrors {
+			if werr.Code == 11000 {
+				return ErrDuplicate
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package mongorepo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Identifiable lets a document type control how its own identity is
+// read and written. Implement it when a type's _id isn't a plain
+// top-level field (e.g. it's derived, or the struct embeds its ID).
+type Identifiable interface {
+	GetID() any
+	SetID(id any)
+}
+
+// getID and setID are Repository's single point of access to a
+// document's _id. Types implementing Identifiable are used directly;
+// everything else falls back to reflecting over the struct for a field
+// tagged `bson:"_id"` (or named ID), which covers the common cases of a
+// primitive.ObjectID or a string id without requiring any boilerplate.
+
+func getID(doc any) any {
+	if idr, ok := doc.(Identifiable); ok {
+		return idr.GetID()
+	}
+	return idField(doc).Interface()
+}
+
+func setID(doc any, id any) {
+	if idr, ok := doc.(Identifiable); ok {
+		idr.SetID(id)
+		return
+	}
+	idField(doc).Set(reflect.ValueOf(id))
+}
+
+func idField(doc any) reflect.Value {
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("mongorepo: %T must be a pointer", doc))
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("bson"), ",")[0]
+		if name == "_id" || f.Name == "ID" {
+			return v.Field(i)
+		}
+	}
+	panic(fmt.Sprintf("mongorepo: %T has no _id field and does not implement Identifiable", doc))
+}
@@ -0,0 +1,148 @@
+// Package mongorepo wraps a *mongo.Collection in a typed Repository so
+// recipes can work with the concrete Go struct instead of bson.M, without
+// giving up the driver's options and cursor APIs.
+package mongorepo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository wraps col and decodes every operation's result into T.
+type Repository[T any] struct {
+	col *mongo.Collection
+}
+
+// NewRepository returns a Repository backed by col.
+func NewRepository[T any](col *mongo.Collection) *Repository[T] {
+	return &Repository[T]{col: col}
+}
+
+// FindByID looks up the document whose _id equals id.
+func (r *Repository[T]) FindByID(ctx context.Context, id any) (*T, error) {
+	return r.FindOne(ctx, bson.M{"_id": id})
+}
+
+// FindOne returns the first document matching filter.
+func (r *Repository[T]) FindOne(ctx context.Context, filter bson.M) (*T, error) {
+	var doc T
+	if err := r.col.FindOne(ctx, filter).Decode(&doc); err != nil {
+		return nil, translateErr(err)
+	}
+	return &doc, nil
+}
+
+// Find returns every document matching filter.
+func (r *Repository[T]) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]T, error) {
+	cursor, err := r.col.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []T
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, translateErr(err)
+	}
+	return docs, nil
+}
+
+// Stream is like Find but decodes lazily onto a channel, so a caller can
+// start processing before the cursor is exhausted. Closing ctx stops
+// iteration and releases the cursor. The error channel carries at most
+// one translateErr'd error — a decode failure or a cursor error at
+// exhaustion — and is closed alongside out once streaming ends.
+func (r *Repository[T]) Stream(ctx context.Context, filter bson.M) (<-chan T, <-chan error) {
+	errc := make(chan error, 1)
+
+	cursor, err := r.col.Find(ctx, filter)
+	if err != nil {
+		out := make(chan T)
+		close(out)
+		errc <- translateErr(err)
+		close(errc)
+		return out, errc
+	}
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		defer close(errc)
+		defer cursor.Close(ctx)
+		for cursor.Next(ctx) {
+			var doc T
+			if err := cursor.Decode(&doc); err != nil {
+				errc <- translateErr(err)
+				return
+			}
+			select {
+			case out <- doc:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			errc <- translateErr(err)
+		}
+	}()
+	return out, errc
+}
+
+// InsertOne inserts doc, writing the result back onto it. A doc whose
+// _id is the zero primitive.ObjectID gets one generated before the
+// insert: the bson "_id" key is always present once a struct field is
+// tagged for it, so the driver never sees an id-less document to
+// auto-generate one for.
+func (r *Repository[T]) InsertOne(ctx context.Context, doc *T) error {
+	if oid, ok := getID(doc).(primitive.ObjectID); ok && oid.IsZero() {
+		setID(doc, primitive.NewObjectID())
+	}
+	res, err := r.col.InsertOne(ctx, doc)
+	if err != nil {
+		return translateErr(err)
+	}
+	setID(doc, res.InsertedID)
+	return nil
+}
+
+// ReplaceByID replaces the document with doc's own _id, creating it if
+// it doesn't already exist.
+func (r *Repository[T]) ReplaceByID(ctx context.Context, doc *T) error {
+	id := getID(doc)
+	_, err := r.col.ReplaceOne(ctx, bson.M{"_id": id}, doc, options.Replace().SetUpsert(true))
+	return translateErr(err)
+}
+
+// UpdateByID applies update to the document whose _id equals id.
+func (r *Repository[T]) UpdateByID(ctx context.Context, id any, update bson.M) error {
+	res, err := r.col.UpdateByID(ctx, id, update)
+	if err != nil {
+		return translateErr(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByID removes the document whose _id equals id.
+func (r *Repository[T]) DeleteByID(ctx context.Context, id any) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return translateErr(err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Count returns the number of documents matching filter.
+func (r *Repository[T]) Count(ctx context.Context, filter bson.M) (int64, error) {
+	n, err := r.col.CountDocuments(ctx, filter)
+	return n, translateErr(err)
+}
@@ -0,0 +1,29 @@
+// Package mongosession helps recipes share a single multi-document
+// transaction across nested function calls without threading a
+// mongo.SessionContext argument through every signature.
+package mongosession
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ctxKey is an unexported type so values stashed by this package never
+// collide with keys set by other packages.
+type ctxKey struct{}
+
+// WithSessionContext returns a copy of ctx carrying sc, so that code
+// further down the call stack can recover it via FromContext and keep
+// operating inside the same transaction.
+func WithSessionContext(ctx context.Context, sc mongo.SessionContext) context.Context {
+	return context.WithValue(ctx, ctxKey{}, sc)
+}
+
+// FromContext recovers the mongo.SessionContext previously stored by
+// WithSessionContext. ok is false when ctx does not carry one, in which
+// case callers should fall back to running outside a transaction.
+func FromContext(ctx context.Context) (sc mongo.SessionContext, ok bool) {
+	sc, ok = ctx.Value(ctxKey{}).(mongo.SessionContext)
+	return sc, ok
+}
@@ -0,0 +1,45 @@
+package mongosession
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// TxnOptions is the read/write concern and read preference the cookbook
+// uses for every transaction: a snapshot read concern paired with a
+// majority write concern guarantees the transaction is both causally
+// consistent and durable once committed.
+var TxnOptions = options.Transaction().
+	SetReadConcern(readconcern.Snapshot()).
+	SetWriteConcern(writeconcern.New(writeconcern.WMajority())).
+	SetReadPreference(readpref.Primary())
+
+// Fn is the unit of work run inside a transaction. Implementations
+// should recover the session via FromContext if they need to run
+// further operations on it, and must return a non-nil error to abort
+// the transaction.
+type Fn func(ctx context.Context) error
+
+// Run starts a session, stashes it on ctx via WithSessionContext and
+// executes fn inside a transaction, committing on success and aborting
+// on error. session.WithTransaction already retries the whole
+// transaction on a TransientTransactionError, and retries just the
+// commit on an UnknownTransactionCommitResult, so callers don't need
+// to handle either label themselves.
+func Run(ctx context.Context, client *mongo.Client, fn Fn) error {
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(WithSessionContext(sessCtx, sessCtx))
+	}, TxnOptions)
+	return err
+}